@@ -1,18 +1,52 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
 const syncFile = "shape-sync.json"
 
+// syncFileMu serializes reads/writes of the shared shape-sync.json file,
+// since streaming mode can run several pushes concurrently; without it two
+// in-flight writes could interleave and corrupt the file for the next pull.
+var syncFileMu sync.Mutex
+
+// writeSyncFileAtomic writes data to a temp file alongside syncFile and
+// renames it into place, so a crash or a write racing with a concurrent
+// push never leaves a corrupted file behind.
+func writeSyncFileAtomic(data []byte) error {
+	syncFileMu.Lock()
+	defer syncFileMu.Unlock()
+
+	dir := filepath.Dir(syncFile)
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, syncFile)
+}
+
 func handleTest() PluginResponse {
 	return PluginResponse{Success: true}
 }
 
-func handlePush(params json.RawMessage) PluginResponse {
+func handlePush(ctx context.Context, params json.RawMessage) PluginResponse {
 	var p PushParams
 	if err := json.Unmarshal(params, &p); err != nil {
 		return PluginResponse{Success: false, Error: "invalid params: " + err.Error()}
@@ -25,6 +59,11 @@ func handlePush(params json.RawMessage) PluginResponse {
 		p.Tasks = []json.RawMessage{}
 	}
 
+	if cached, ok := checkIdempotency(p.IdempotencyKey); ok {
+		resultJSON, _ := json.Marshal(cached)
+		return PluginResponse{Success: true, Data: resultJSON}
+	}
+
 	data := SyncFile{
 		Briefs:   p.Briefs,
 		Tasks:    p.Tasks,
@@ -36,7 +75,12 @@ func handlePush(params json.RawMessage) PluginResponse {
 		return PluginResponse{Success: false, Error: "failed to marshal: " + err.Error()}
 	}
 
-	if err := os.WriteFile(syncFile, content, 0644); err != nil {
+	if err := runWithContext(ctx, func() error {
+		return writeSyncFileAtomic(content)
+	}); err != nil {
+		if err == ctx.Err() {
+			return deadlineResponse(SyncResult{Errors: []string{}, Mappings: []json.RawMessage{}})
+		}
 		return PluginResponse{Success: false, Error: "failed to write file: " + err.Error()}
 	}
 
@@ -48,12 +92,21 @@ func handlePush(params json.RawMessage) PluginResponse {
 		Mappings:  []json.RawMessage{},
 	}
 
+	if err := recordIdempotency(p.IdempotencyKey, result); err != nil {
+		result.Errors = append(result.Errors, "failed to record idempotency key: "+err.Error())
+	}
+
 	resultJSON, _ := json.Marshal(result)
 	return PluginResponse{Success: true, Data: resultJSON}
 }
 
-func handlePull() PluginResponse {
-	content, err := os.ReadFile(syncFile)
+func handlePull(ctx context.Context) PluginResponse {
+	var content []byte
+	err := runWithContext(ctx, func() error {
+		var readErr error
+		content, readErr = os.ReadFile(syncFile)
+		return readErr
+	})
 	if err != nil {
 		if os.IsNotExist(err) {
 			result := SyncResult{
@@ -107,14 +160,14 @@ func handleStatus() PluginResponse {
 	return PluginResponse{Success: true, Data: resultJSON}
 }
 
-func handleRequest(req PluginRequest) PluginResponse {
+func handleRequest(ctx context.Context, req PluginRequest) PluginResponse {
 	switch req.Operation {
 	case "test":
 		return handleTest()
 	case "push":
-		return handlePush(req.Params)
+		return handlePush(ctx, req.Params)
 	case "pull":
-		return handlePull()
+		return handlePull(ctx)
 	case "status":
 		return handleStatus()
 	default:
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const idempotencyDir = ".shape-sync"
+const idempotencyFileName = "idempotency.json"
+
+// idempotencyMu serializes load-modify-save access to the idempotency
+// store file, since streaming mode can run several pushes concurrently.
+var idempotencyMu sync.Mutex
+
+// idempotencyTTL bounds how long a cached push result is honored; entries
+// older than this are dropped the next time the store is loaded so the file
+// doesn't grow forever.
+const idempotencyTTL = 24 * time.Hour
+
+// idempotencyEntry is a cached push result keyed by the caller-supplied
+// idempotency key that produced it.
+type idempotencyEntry struct {
+	Result   SyncResult `json:"result"`
+	StoredAt time.Time  `json:"stored_at"`
+}
+
+type idempotencyStore map[string]idempotencyEntry
+
+func idempotencyPath() string {
+	return filepath.Join(idempotencyDir, idempotencyFileName)
+}
+
+// loadIdempotencyStore reads the store from disk, dropping any entries
+// older than idempotencyTTL. A missing or corrupt file yields an empty
+// store rather than an error.
+func loadIdempotencyStore() idempotencyStore {
+	store := idempotencyStore{}
+
+	data, err := os.ReadFile(idempotencyPath())
+	if err != nil {
+		return store
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return idempotencyStore{}
+	}
+
+	cutoff := time.Now().Add(-idempotencyTTL)
+	for key, entry := range store {
+		if entry.StoredAt.Before(cutoff) {
+			delete(store, key)
+		}
+	}
+	return store
+}
+
+func saveIdempotencyStore(store idempotencyStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(idempotencyDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(idempotencyPath(), data, 0644)
+}
+
+// checkIdempotency reports whether key already has a cached result on
+// disk, in which case handlePush should short-circuit and hand that result
+// back unchanged instead of rewriting the sync file.
+func checkIdempotency(key string) (SyncResult, bool) {
+	if key == "" {
+		return SyncResult{}, false
+	}
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+	entry, ok := loadIdempotencyStore()[key]
+	return entry.Result, ok
+}
+
+// recordIdempotency persists result under key so a retried push with the
+// same key can be answered from cache.
+func recordIdempotency(key string, result SyncResult) error {
+	if key == "" {
+		return nil
+	}
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+	store := loadIdempotencyStore()
+	store[key] = idempotencyEntry{Result: result, StoredAt: time.Now()}
+	return saveIdempotencyStore(store)
+}
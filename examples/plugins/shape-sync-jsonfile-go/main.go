@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxLineSize bounds a single NDJSON request line; large enough for a
+// sizable brief body without letting a malformed stream grow unbounded.
+const maxLineSize = 10 * 1024 * 1024
+
+// streamWorkers is the number of requests processed concurrently in
+// streaming mode.
+const streamWorkers = 4
+
+var manifest = Manifest{
+	Name:         "shape-sync-jsonfile-go",
+	Version:      "1.0.0",
+	Description:  "Sync briefs to a local JSON file (Go)",
+	Type:         "sync",
+	Operations:   []string{"push", "pull", "status", "test"},
+	Capabilities: []string{"streaming"},
+}
+
+func printManifest() {
+	json.NewEncoder(os.Stdout).Encode(manifest)
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "--manifest" {
+		printManifest()
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "--legacy" {
+		runLegacy()
+		return
+	}
+
+	runStreaming()
+}
+
+// runLegacy reads exactly one request from stdin, answers it, and exits.
+// Kept for callers that still fork+exec the plugin per operation.
+func runLegacy() {
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read input: %v\n", err)
+		os.Exit(1)
+	}
+
+	resp := handleLine([]byte(line))
+	json.NewEncoder(os.Stdout).Encode(resp)
+}
+
+// runStreaming keeps the process alive and serves requests as newline-
+// delimited JSON over stdin/stdout, avoiding a fork+exec per operation.
+// Requests are fanned out to a small worker pool so a slow push/pull
+// doesn't block other in-flight requests; each response carries the same
+// RequestID as its request so shape-cli can match them up out of order.
+func runStreaming() {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	jobs := make(chan []byte)
+	results := make(chan PluginResponse)
+
+	var workers sync.WaitGroup
+	for i := 0; i < streamWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for line := range jobs {
+				results <- handleLine(line)
+			}
+		}()
+	}
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	writerDone := make(chan struct{})
+	go func() {
+		encoder := json.NewEncoder(os.Stdout)
+		for resp := range results {
+			encoder.Encode(resp)
+		}
+		close(writerDone)
+	}()
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		jobs <- append([]byte(nil), line...)
+	}
+	close(jobs)
+	<-writerDone
+
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "stdin read error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func handleLine(line []byte) PluginResponse {
+	var req PluginRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return PluginResponse{Success: false, Error: "invalid request: " + err.Error()}
+	}
+
+	ctx, cancel := requestContext(req)
+	defer cancel()
+
+	resp := handleRequest(ctx, req)
+	resp.RequestID = req.RequestID
+	return resp
+}
+
+// requestContext builds the context a single request is handled under. In
+// streaming mode the process lives far longer than any one request, so the
+// cancel func is always deferred right after use instead of being kept
+// around - each request's timer is released as soon as that request
+// finishes, and nothing accumulates over the life of the process.
+func requestContext(req PluginRequest) (context.Context, context.CancelFunc) {
+	if req.Deadline != "" {
+		if deadline, err := time.Parse(time.RFC3339, req.Deadline); err == nil {
+			return context.WithDeadline(context.Background(), deadline)
+		}
+	}
+	if req.TimeoutMs > 0 {
+		return context.WithTimeout(context.Background(), time.Duration(req.TimeoutMs)*time.Millisecond)
+	}
+	return context.WithCancel(context.Background())
+}
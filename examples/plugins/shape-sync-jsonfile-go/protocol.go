@@ -2,23 +2,33 @@ package main
 
 import "encoding/json"
 
-// PluginRequest represents an incoming request from shape-cli.
+// PluginRequest represents an incoming request from shape-cli. Deadline, if
+// set, takes precedence over TimeoutMs; both are optional.
 type PluginRequest struct {
+	RequestID string          `json:"request_id,omitempty"`
 	Operation string          `json:"operation"`
 	Params    json.RawMessage `json:"params"`
+	TimeoutMs int             `json:"timeout_ms,omitempty"`
+	Deadline  string          `json:"deadline,omitempty"` // RFC3339
 }
 
-// PluginResponse represents a response to shape-cli.
+// PluginResponse represents a response to shape-cli. In streaming mode,
+// RequestID echoes the request it answers so callers can match responses
+// that arrive out of order.
 type PluginResponse struct {
-	Success bool            `json:"success"`
-	Data    json.RawMessage `json:"data,omitempty"`
-	Error   string          `json:"error,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Success   bool            `json:"success"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     string          `json:"error,omitempty"`
 }
 
-// PushParams contains the parameters for a push operation.
+// PushParams contains the parameters for a push operation. IdempotencyKey,
+// if set, lets a retried push after a partial failure be answered from the
+// cached SyncResult instead of rewriting the sync file a second time.
 type PushParams struct {
-	Briefs []json.RawMessage `json:"briefs"`
-	Tasks  []json.RawMessage `json:"tasks"`
+	Briefs         []json.RawMessage `json:"briefs"`
+	Tasks          []json.RawMessage `json:"tasks"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
 }
 
 // SyncResult contains the result data for push/pull operations.
@@ -47,9 +57,10 @@ type SyncFile struct {
 
 // Manifest describes the plugin's capabilities.
 type Manifest struct {
-	Name        string   `json:"name"`
-	Version     string   `json:"version"`
-	Description string   `json:"description"`
-	Type        string   `json:"type"`
-	Operations  []string `json:"operations"`
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Description  string   `json:"description"`
+	Type         string   `json:"type"`
+	Operations   []string `json:"operations"`
+	Capabilities []string `json:"capabilities,omitempty"`
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// runWithContext runs fn in a goroutine and waits for it to finish or for
+// ctx to be canceled or its deadline to expire, whichever comes first. fn
+// keeps running in the background even after a timeout fires; the caller
+// just stops waiting on it so a hung S3 or Git backend can't block the
+// whole request.
+func runWithContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deadlineResponse reports a deadline-exceeded error back to shape-cli
+// alongside however much of result was completed before the deadline fired,
+// so the caller knows what it still needs to retry.
+func deadlineResponse(result SyncResult) PluginResponse {
+	data, _ := json.Marshal(result)
+	return PluginResponse{Success: false, Error: "deadline exceeded", Data: data}
+}
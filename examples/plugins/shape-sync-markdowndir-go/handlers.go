@@ -1,10 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
@@ -19,7 +19,7 @@ func handleTest() PluginResponse {
 	return PluginResponse{Success: true}
 }
 
-func handlePush(params json.RawMessage) PluginResponse {
+func handlePush(ctx context.Context, params json.RawMessage) PluginResponse {
 	var p PushParams
 	if err := json.Unmarshal(params, &p); err != nil {
 		return PluginResponse{Success: false, Error: "invalid params: " + err.Error()}
@@ -30,18 +30,130 @@ func handlePush(params json.RawMessage) PluginResponse {
 	}
 
 	outputDir := getOutputDir()
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return PluginResponse{Success: false, Error: "failed to create directory: " + err.Error()}
+
+	if cached, ok := checkIdempotency(outputDir, p.IdempotencyKey); ok {
+		resultJSON, _ := json.Marshal(cached)
+		return PluginResponse{Success: true, Data: resultJSON}
+	}
+
+	storage, err := newStorage(outputDir)
+	if err != nil {
+		return PluginResponse{Success: false, Error: "failed to initialize storage: " + err.Error()}
 	}
 
 	mappings := make([]IdMapping, 0, len(p.Briefs))
+	errs := []string{}
+	pushed := 0
+	conflicts := 0
+
 	for _, brief := range p.Briefs {
+		select {
+		case <-ctx.Done():
+			return deadlineResponse(SyncResult{
+				Pushed:    pushed,
+				Conflicts: conflicts,
+				Errors:    errs,
+				Mappings:  mappings,
+			})
+		default:
+		}
+
 		filename := fmt.Sprintf("%s-%s.md", brief.ID, slugify(brief.Title))
-		filepath := filepath.Join(outputDir, filename)
 
-		content := generateMarkdown(brief)
-		if err := os.WriteFile(filepath, []byte(content), 0644); err != nil {
-			return PluginResponse{Success: false, Error: "failed to write file: " + err.Error()}
+		var localBrief Brief
+		hasLocal := false
+		if existing, err := storage.Get(filename); err == nil {
+			if b, ok := parseMarkdown(string(existing)); ok {
+				localBrief, hasLocal = b, true
+			}
+		}
+
+		snap, hasSnap := loadSnapshot(outputDir, brief.ID)
+
+		switch {
+		case !hasSnap:
+			// First sync for this brief: nothing to reconcile against yet.
+			if err := runWithContext(ctx, func() error {
+				return storage.Put(filename, []byte(generateMarkdown(brief)))
+			}); err != nil {
+				if err == ctx.Err() {
+					return deadlineResponse(SyncResult{
+						Pushed:    pushed,
+						Conflicts: conflicts,
+						Errors:    errs,
+						Mappings:  mappings,
+					})
+				}
+				return PluginResponse{Success: false, Error: "failed to write file: " + err.Error()}
+			}
+			if err := saveSnapshot(outputDir, brief); err != nil {
+				return PluginResponse{Success: false, Error: "failed to write snapshot: " + err.Error()}
+			}
+			pushed++
+
+		default:
+			localChanged := hasLocal && contentHash(stableContent(localBrief)) != snap.Hash
+			remoteChanged := contentHash(stableContent(brief)) != snap.Hash
+
+			switch {
+			case !localChanged && !remoteChanged:
+				// Neither side moved since the last sync; leave it alone.
+
+			case !localChanged && remoteChanged:
+				if err := runWithContext(ctx, func() error {
+					return storage.Put(filename, []byte(generateMarkdown(brief)))
+				}); err != nil {
+					if err == ctx.Err() {
+						return deadlineResponse(SyncResult{
+							Pushed:    pushed,
+							Conflicts: conflicts,
+							Errors:    errs,
+							Mappings:  mappings,
+						})
+					}
+					return PluginResponse{Success: false, Error: "failed to write file: " + err.Error()}
+				}
+				if err := saveSnapshot(outputDir, brief); err != nil {
+					return PluginResponse{Success: false, Error: "failed to write snapshot: " + err.Error()}
+				}
+				pushed++
+
+			case localChanged && !remoteChanged:
+				// The markdown file was edited locally but shape-cli has
+				// nothing new for this brief; keep the local edit and adopt
+				// it as the new sync baseline.
+				if err := saveSnapshot(outputDir, localBrief); err != nil {
+					return PluginResponse{Success: false, Error: "failed to write snapshot: " + err.Error()}
+				}
+
+			default:
+				mergedBody, conflict := mergeBodies(snap.Body, localBrief.Body, brief.Body)
+				merged := brief
+				merged.Body = mergedBody
+
+				if err := runWithContext(ctx, func() error {
+					return storage.Put(filename, []byte(generateMarkdown(merged)))
+				}); err != nil {
+					if err == ctx.Err() {
+						return deadlineResponse(SyncResult{
+							Pushed:    pushed,
+							Conflicts: conflicts,
+							Errors:    errs,
+							Mappings:  mappings,
+						})
+					}
+					return PluginResponse{Success: false, Error: "failed to write file: " + err.Error()}
+				}
+				if conflict {
+					conflicts++
+					errs = append(errs, filename)
+				} else {
+					if err := saveSnapshot(outputDir, merged); err != nil {
+						return PluginResponse{Success: false, Error: "failed to write snapshot: " + err.Error()}
+					}
+					pushed++
+				}
+			}
 		}
 
 		mappings = append(mappings, IdMapping{
@@ -51,101 +163,169 @@ func handlePush(params json.RawMessage) PluginResponse {
 		})
 	}
 
+	taskMappings, taskErrs, taskErr := pushTasks(ctx, storage, p.Tasks)
+	mappings = append(mappings, taskMappings...)
+	errs = append(errs, taskErrs...)
+	if taskErr != nil {
+		return deadlineResponse(SyncResult{
+			Pushed:    pushed,
+			Conflicts: conflicts,
+			Errors:    errs,
+			Mappings:  mappings,
+		})
+	}
+
+	if git, ok := storage.(*gitStorage); ok {
+		if err := git.commitAndPush(pushed); err != nil {
+			errs = append(errs, "git commit/push failed: "+err.Error())
+		}
+	}
+
 	result := SyncResult{
-		Pushed:    len(p.Briefs),
+		Pushed:    pushed,
 		Pulled:    0,
-		Conflicts: 0,
-		Errors:    []string{},
+		Conflicts: conflicts,
+		Errors:    errs,
 		Mappings:  mappings,
 	}
 
+	if err := recordIdempotency(outputDir, p.IdempotencyKey, result); err != nil {
+		result.Errors = append(result.Errors, "failed to record idempotency key: "+err.Error())
+	}
+
 	resultJSON, _ := json.Marshal(result)
 	return PluginResponse{Success: true, Data: resultJSON}
 }
 
-func handlePull() PluginResponse {
+func handlePull(ctx context.Context) PluginResponse {
 	outputDir := getOutputDir()
-
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
-		result := SyncResult{
-			Briefs:    []Brief{},
-			Tasks:     []any{},
-			Mappings:  []IdMapping{},
-			Pushed:    0,
-			Pulled:    0,
-			Conflicts: 0,
-			Errors:    []string{},
-		}
-		resultJSON, _ := json.Marshal(result)
-		return PluginResponse{Success: true, Data: resultJSON}
+	storage, err := newStorage(outputDir)
+	if err != nil {
+		return PluginResponse{Success: false, Error: "failed to initialize storage: " + err.Error()}
 	}
 
-	entries, err := os.ReadDir(outputDir)
+	names, err := storage.List()
 	if err != nil {
-		return PluginResponse{Success: false, Error: "failed to read directory: " + err.Error()}
+		if os.IsNotExist(err) {
+			result := SyncResult{
+				Briefs:    []Brief{},
+				Tasks:     []Task{},
+				Mappings:  []IdMapping{},
+				Pushed:    0,
+				Pulled:    0,
+				Conflicts: 0,
+				Errors:    []string{},
+			}
+			resultJSON, _ := json.Marshal(result)
+			return PluginResponse{Success: true, Data: resultJSON}
+		}
+		return PluginResponse{Success: false, Error: "failed to list files: " + err.Error()}
 	}
 
 	briefs := []Brief{}
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+	errs := []string{}
+	conflicts := 0
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			return deadlineResponse(SyncResult{
+				Briefs:    briefs,
+				Tasks:     collectTasks(ctx, storage),
+				Mappings:  []IdMapping{},
+				Pulled:    len(briefs),
+				Conflicts: conflicts,
+				Errors:    errs,
+			})
+		default:
+		}
+
+		if !strings.HasSuffix(name, ".md") || strings.Contains(name, "/") {
+			// Skip sidecar task files under tasks/<brief-id>/; only
+			// top-level files are brief markdown. collectTasks handles both.
+			continue
+		}
+
+		var raw []byte
+		if err := runWithContext(ctx, func() error {
+			var getErr error
+			raw, getErr = storage.Get(name)
+			return getErr
+		}); err != nil {
 			continue
 		}
 
-		content, err := os.ReadFile(filepath.Join(outputDir, entry.Name()))
-		if err != nil {
+		content, _, _ := splitTasksSection(string(raw))
+
+		if hasConflictMarkers(content) {
+			conflicts++
+			errs = append(errs, name+": unresolved merge conflict")
 			continue
 		}
 
-		if brief, ok := parseMarkdown(string(content)); ok {
+		if brief, ok := parseMarkdown(content); ok {
 			briefs = append(briefs, brief)
 		}
 	}
 
 	result := SyncResult{
 		Briefs:    briefs,
-		Tasks:     []any{},
+		Tasks:     collectTasks(ctx, storage),
 		Mappings:  []IdMapping{},
 		Pushed:    0,
 		Pulled:    len(briefs),
-		Conflicts: 0,
-		Errors:    []string{},
+		Conflicts: conflicts,
+		Errors:    errs,
 	}
 
 	resultJSON, _ := json.Marshal(result)
 	return PluginResponse{Success: true, Data: resultJSON}
 }
 
-func handleStatus() PluginResponse {
+func handleStatus(ctx context.Context) PluginResponse {
 	outputDir := getOutputDir()
 	count := 0
+	taskCount := 0
 
-	if entries, err := os.ReadDir(outputDir); err == nil {
-		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".md") {
-				count++
+	if storage, err := newStorage(outputDir); err == nil {
+		var names []string
+		listErr := runWithContext(ctx, func() error {
+			var err error
+			names, err = storage.List()
+			return err
+		})
+		if listErr != nil {
+			if listErr == ctx.Err() {
+				return PluginResponse{Success: false, Error: "deadline exceeded"}
+			}
+		} else {
+			for _, name := range names {
+				if strings.HasSuffix(name, ".md") && !strings.Contains(name, "/") {
+					count++
+				}
 			}
+			taskCount = len(collectTasks(ctx, storage))
 		}
 	}
 
 	result := StatusResult{
 		MappedBriefs: count,
-		MappedTasks:  0,
+		MappedTasks:  taskCount,
 	}
 
 	resultJSON, _ := json.Marshal(result)
 	return PluginResponse{Success: true, Data: resultJSON}
 }
 
-func handleRequest(req PluginRequest) PluginResponse {
+func handleRequest(ctx context.Context, req PluginRequest) PluginResponse {
 	switch req.Operation {
 	case "test":
 		return handleTest()
 	case "push":
-		return handlePush(req.Params)
+		return handlePush(ctx, req.Params)
 	case "pull":
-		return handlePull()
+		return handlePull(ctx)
 	case "status":
-		return handleStatus()
+		return handleStatus(ctx)
 	default:
 		return PluginResponse{Success: false, Error: "Unknown operation: " + req.Operation}
 	}
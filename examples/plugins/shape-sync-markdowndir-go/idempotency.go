@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const idempotencyFileName = "idempotency.json"
+
+// idempotencyMu serializes load-modify-save access to the idempotency
+// store file, since streaming mode can run several pushes concurrently
+// against the same outputDir.
+var idempotencyMu sync.Mutex
+
+// idempotencyTTL bounds how long a cached push result is honored; entries
+// older than this are dropped the next time the store is loaded so the file
+// doesn't grow forever.
+const idempotencyTTL = 24 * time.Hour
+
+// idempotencyEntry is a cached push result keyed by the caller-supplied
+// idempotency key that produced it.
+type idempotencyEntry struct {
+	Result   SyncResult `json:"result"`
+	StoredAt time.Time  `json:"stored_at"`
+}
+
+type idempotencyStore map[string]idempotencyEntry
+
+func idempotencyPath(outputDir string) string {
+	return filepath.Join(snapshotDir(outputDir), idempotencyFileName)
+}
+
+// loadIdempotencyStore reads the store from disk, dropping any entries
+// older than idempotencyTTL. A missing or corrupt file yields an empty
+// store rather than an error, matching loadSnapshot's best-effort style.
+func loadIdempotencyStore(outputDir string) idempotencyStore {
+	store := idempotencyStore{}
+
+	data, err := os.ReadFile(idempotencyPath(outputDir))
+	if err != nil {
+		return store
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return idempotencyStore{}
+	}
+
+	cutoff := time.Now().Add(-idempotencyTTL)
+	for key, entry := range store {
+		if entry.StoredAt.Before(cutoff) {
+			delete(store, key)
+		}
+	}
+	return store
+}
+
+func saveIdempotencyStore(outputDir string, store idempotencyStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(idempotencyPath(outputDir), data)
+}
+
+// checkIdempotency reports whether key already has a cached result on
+// disk, in which case handlePush should short-circuit and hand that result
+// back unchanged instead of re-running the file writes.
+func checkIdempotency(outputDir, key string) (SyncResult, bool) {
+	if key == "" {
+		return SyncResult{}, false
+	}
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+	entry, ok := loadIdempotencyStore(outputDir)[key]
+	return entry.Result, ok
+}
+
+// recordIdempotency persists result under key so a retried push with the
+// same key can be answered from cache.
+func recordIdempotency(outputDir, key string, result SyncResult) error {
+	if key == "" {
+		return nil
+	}
+	idempotencyMu.Lock()
+	defer idempotencyMu.Unlock()
+	store := loadIdempotencyStore(outputDir)
+	store[key] = idempotencyEntry{Result: result, StoredAt: time.Now()}
+	return saveIdempotencyStore(outputDir, store)
+}
@@ -2,10 +2,11 @@ package main
 
 import (
 	"bytes"
-	"fmt"
 	"regexp"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
@@ -18,18 +19,23 @@ func slugify(title string) string {
 	return slug
 }
 
-// generateMarkdown creates markdown content with YAML frontmatter for a brief.
+// generateMarkdown creates markdown content with YAML frontmatter for a
+// brief. Brief itself carries the frontmatter's yaml tags (see protocol.go),
+// so it's marshaled directly rather than through a hand-maintained shadow
+// struct: a field added to Brief shows up in the frontmatter for free.
 func generateMarkdown(brief Brief) string {
-	var buf bytes.Buffer
+	brief.SyncedAt = time.Now().UTC().Format(time.RFC3339)
 
-	buf.WriteString("---\n")
-	buf.WriteString(fmt.Sprintf("id: %s\n", brief.ID))
-	buf.WriteString(fmt.Sprintf("title: %q\n", brief.Title))
-	buf.WriteString(fmt.Sprintf("status: %s\n", brief.Status))
-	if brief.Appetite != "" {
-		buf.WriteString(fmt.Sprintf("appetite: %s\n", brief.Appetite))
+	header, err := yaml.Marshal(brief)
+	if err != nil {
+		// Every declared field is a YAML-safe scalar; this only trips if
+		// Extra smuggles in a value yaml.v3 can't encode.
+		header = []byte("id: " + brief.ID + "\n")
 	}
-	buf.WriteString(fmt.Sprintf("synced_at: %s\n", time.Now().UTC().Format(time.RFC3339)))
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
 	buf.WriteString("---\n\n")
 	buf.WriteString(brief.Body)
 
@@ -38,44 +44,26 @@ func generateMarkdown(brief Brief) string {
 
 // parseMarkdown parses markdown content with YAML frontmatter into a Brief.
 func parseMarkdown(content string) (Brief, bool) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
 	if !strings.HasPrefix(content, "---\n") {
 		return Brief{}, false
 	}
 
-	endIndex := strings.Index(content[4:], "\n---\n")
+	rest := content[4:]
+	endIndex := strings.Index(rest, "\n---\n")
 	if endIndex == -1 {
 		return Brief{}, false
 	}
 
-	frontmatter := content[4 : 4+endIndex]
-	body := strings.TrimPrefix(content[4+endIndex+5:], "\n")
-
-	brief := Brief{Body: body}
-
-	for _, line := range strings.Split(frontmatter, "\n") {
-		parts := strings.SplitN(line, ": ", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := parts[0]
-		value := parts[1]
+	header := rest[:endIndex]
+	body := strings.TrimPrefix(rest[endIndex+5:], "\n")
 
-		// Remove surrounding quotes if present
-		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
-			value = value[1 : len(value)-1]
-		}
-
-		switch key {
-		case "id":
-			brief.ID = value
-		case "title":
-			brief.Title = value
-		case "status":
-			brief.Status = value
-		case "appetite":
-			brief.Appetite = value
-		}
+	var brief Brief
+	if err := yaml.Unmarshal([]byte(header), &brief); err != nil {
+		return Brief{}, false
 	}
+	brief.Body = body
 
 	return brief, brief.ID != ""
 }
@@ -0,0 +1,91 @@
+package main
+
+import "testing"
+
+func TestGenerateAndParseMarkdownRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		brief Brief
+	}{
+		{
+			name:  "title with quotes",
+			brief: Brief{ID: "b1", Title: `The "Big" Rewrite`, Status: "in-progress"},
+		},
+		{
+			name:  "title with colon",
+			brief: Brief{ID: "b2", Title: "Sync: bidirectional mode", Status: "todo", Appetite: "big"},
+		},
+		{
+			name:  "title with emoji",
+			brief: Brief{ID: "b3", Title: "Ship it 🚀 faster", Status: "done"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.brief.Body = "Some body text.\n\nWith a second paragraph."
+			content := generateMarkdown(tc.brief)
+
+			parsed, ok := parseMarkdown(content)
+			if !ok {
+				t.Fatalf("parseMarkdown failed to parse generated content:\n%s", content)
+			}
+
+			if parsed.ID != tc.brief.ID {
+				t.Errorf("ID = %q, want %q", parsed.ID, tc.brief.ID)
+			}
+			if parsed.Title != tc.brief.Title {
+				t.Errorf("Title = %q, want %q", parsed.Title, tc.brief.Title)
+			}
+			if parsed.Status != tc.brief.Status {
+				t.Errorf("Status = %q, want %q", parsed.Status, tc.brief.Status)
+			}
+			if parsed.Appetite != tc.brief.Appetite {
+				t.Errorf("Appetite = %q, want %q", parsed.Appetite, tc.brief.Appetite)
+			}
+			if parsed.Body != tc.brief.Body {
+				t.Errorf("Body = %q, want %q", parsed.Body, tc.brief.Body)
+			}
+		})
+	}
+}
+
+func TestParseMarkdownCRLF(t *testing.T) {
+	content := "---\r\nid: b4\r\ntitle: CRLF brief\r\nstatus: todo\r\n---\r\n\r\nLine one.\r\nLine two.\r\n"
+
+	brief, ok := parseMarkdown(content)
+	if !ok {
+		t.Fatalf("parseMarkdown failed on CRLF content:\n%q", content)
+	}
+	if brief.ID != "b4" || brief.Title != "CRLF brief" || brief.Status != "todo" {
+		t.Errorf("unexpected brief: %+v", brief)
+	}
+	if brief.Body != "Line one.\nLine two.\n" {
+		t.Errorf("Body = %q, want normalized LF line endings", brief.Body)
+	}
+}
+
+func TestParseMarkdownPreservesExtraFields(t *testing.T) {
+	content := "---\nid: b5\ntitle: Extra fields\nstatus: todo\nreviewer: jane\npriority: 2\n---\n\nBody text.\n"
+
+	brief, ok := parseMarkdown(content)
+	if !ok {
+		t.Fatalf("parseMarkdown failed to parse content:\n%s", content)
+	}
+
+	if brief.Extra["reviewer"] != "jane" {
+		t.Errorf("Extra[reviewer] = %v, want %q", brief.Extra["reviewer"], "jane")
+	}
+	if brief.Extra["priority"] != 2 {
+		t.Errorf("Extra[priority] = %v, want 2", brief.Extra["priority"])
+	}
+
+	regenerated := generateMarkdown(brief)
+	roundTripped, ok := parseMarkdown(regenerated)
+	if !ok {
+		t.Fatalf("parseMarkdown failed on regenerated content:\n%s", regenerated)
+	}
+	if roundTripped.Extra["reviewer"] != "jane" {
+		t.Errorf("Extra[reviewer] did not survive a second round trip: %v", roundTripped.Extra["reviewer"])
+	}
+}
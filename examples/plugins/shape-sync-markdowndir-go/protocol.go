@@ -2,31 +2,68 @@ package main
 
 import "encoding/json"
 
-// PluginRequest represents an incoming request from shape-cli.
+// PluginRequest represents an incoming request from shape-cli. Deadline, if
+// set, takes precedence over TimeoutMs; both are optional.
 type PluginRequest struct {
+	RequestID string          `json:"request_id,omitempty"`
 	Operation string          `json:"operation"`
 	Params    json.RawMessage `json:"params"`
+	TimeoutMs int             `json:"timeout_ms,omitempty"`
+	Deadline  string          `json:"deadline,omitempty"` // RFC3339
 }
 
-// PluginResponse represents a response to shape-cli.
+// PluginResponse represents a response to shape-cli. In streaming mode,
+// RequestID echoes the request it answers so callers can match responses
+// that arrive out of order.
 type PluginResponse struct {
-	Success bool            `json:"success"`
-	Data    json.RawMessage `json:"data,omitempty"`
-	Error   string          `json:"error,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Success   bool            `json:"success"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     string          `json:"error,omitempty"`
 }
 
-// PushParams contains the parameters for a push operation.
+// PushParams contains the parameters for a push operation. IdempotencyKey,
+// if set, lets a retried push after a partial failure be answered from the
+// cached SyncResult instead of rewriting files a second time.
 type PushParams struct {
-	Briefs []Brief `json:"briefs"`
+	Briefs         []Brief `json:"briefs"`
+	Tasks          []Task  `json:"tasks"`
+	IdempotencyKey string  `json:"idempotency_key,omitempty"`
 }
 
-// Brief represents a brief from Shape.
+// Brief represents a brief from Shape. The yaml tags double as the
+// markdown plugin's frontmatter schema (see generateMarkdown/parseMarkdown
+// in markdown.go): a field added here flows straight through to the
+// frontmatter without touching the parser. SyncedAt is frontmatter-only
+// metadata, not part of the wire protocol, hence json:"-". Body isn't
+// frontmatter at all; it's the markdown below the "---" fence.
 type Brief struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	Status   string `json:"status"`
-	Appetite string `json:"appetite,omitempty"`
-	Body     string `json:"body"`
+	ID       string         `json:"id" yaml:"id"`
+	Title    string         `json:"title" yaml:"title"`
+	Status   string         `json:"status" yaml:"status"`
+	Appetite string         `json:"appetite,omitempty" yaml:"appetite,omitempty"`
+	SyncedAt string         `json:"-" yaml:"synced_at,omitempty"`
+	Body     string         `json:"body" yaml:"-"`
+	Extra    map[string]any `json:"extra,omitempty" yaml:",inline"`
+}
+
+// ChecklistItem is a single checklist line inside a Task.
+type ChecklistItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// Task represents a task belonging to a brief. As with Brief, the yaml
+// tags are the frontmatter schema used by generateTaskMarkdown/
+// parseTaskMarkdown in tasks.go. Checklist is rendered as markdown
+// checkbox lines in the body, not as frontmatter, hence yaml:"-".
+type Task struct {
+	ID        string          `json:"id" yaml:"id"`
+	BriefID   string          `json:"brief_id" yaml:"brief_id"`
+	Title     string          `json:"title" yaml:"title"`
+	Status    string          `json:"status" yaml:"status"`
+	Assignee  string          `json:"assignee,omitempty" yaml:"assignee,omitempty"`
+	Checklist []ChecklistItem `json:"checklist,omitempty" yaml:"-"`
 }
 
 // IdMapping represents a mapping between local and remote IDs.
@@ -39,7 +76,7 @@ type IdMapping struct {
 // SyncResult contains the result data for push/pull operations.
 type SyncResult struct {
 	Briefs    []Brief     `json:"briefs,omitempty"`
-	Tasks     []any       `json:"tasks,omitempty"`
+	Tasks     []Task      `json:"tasks,omitempty"`
 	Mappings  []IdMapping `json:"mappings"`
 	Pushed    int         `json:"pushed"`
 	Pulled    int         `json:"pulled"`
@@ -55,9 +92,11 @@ type StatusResult struct {
 
 // Manifest describes the plugin's capabilities.
 type Manifest struct {
-	Name        string   `json:"name"`
-	Version     string   `json:"version"`
-	Description string   `json:"description"`
-	Type        string   `json:"type"`
-	Operations  []string `json:"operations"`
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	Description  string   `json:"description"`
+	Type         string   `json:"type"`
+	Operations   []string `json:"operations"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Backends     []string `json:"backends,omitempty"`
 }
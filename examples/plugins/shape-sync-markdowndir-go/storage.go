@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Storage abstracts where synced brief and task markdown lives, so
+// handlePush/handlePull/tasks.go don't need to know whether they're talking
+// to a local directory, an S3-compatible bucket, or a Git repository.
+// Names may contain "/" (e.g. "tasks/<brief-id>/<task-id>-<slug>.md" for the
+// sibling task-file layout); List returns every regular file's name,
+// recursively, as a slash-separated path relative to the backend's root.
+type Storage interface {
+	Put(name string, content []byte) error
+	Get(name string) ([]byte, error)
+	List() ([]string, error)
+	Delete(name string) error
+}
+
+// storageFactories holds one constructor per backend compiled into this
+// binary. S3 support registers itself from a file built only with the "s3"
+// tag, so a binary built without it simply doesn't advertise it; the fs and
+// git backends are always compiled in.
+var storageFactories = map[string]func(outputDir string) (Storage, error){}
+
+func registerStorageBackend(name string, factory func(outputDir string) (Storage, error)) {
+	storageFactories[name] = factory
+}
+
+// compiledBackends lists the storage backends available in this build, for
+// the manifest.
+func compiledBackends() []string {
+	names := make([]string, 0, len(storageFactories))
+	for name := range storageFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newStorage builds the backend selected by SHAPE_SYNC_MARKDOWN_BACKEND,
+// defaulting to the local filesystem.
+func newStorage(outputDir string) (Storage, error) {
+	name := os.Getenv("SHAPE_SYNC_MARKDOWN_BACKEND")
+	if name == "" {
+		name = "fs"
+	}
+
+	factory, ok := storageFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown or not-compiled-in storage backend %q", name)
+	}
+	return factory(outputDir)
+}
+
+func init() {
+	registerStorageBackend("fs", func(outputDir string) (Storage, error) {
+		return &fsStorage{dir: outputDir}, nil
+	})
+}
+
+// fsStorage is the original local-directory backend.
+type fsStorage struct {
+	dir string
+}
+
+func (s *fsStorage) Put(name string, content []byte) error {
+	return writeFileAtomic(filepath.Join(s.dir, name), content)
+}
+
+func (s *fsStorage) Get(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+func (s *fsStorage) List() ([]string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	return listDirRecursive(s.dir)
+}
+
+// listDirRecursive walks dir and returns every regular file's path relative
+// to dir, using "/" as the separator regardless of OS so names round-trip
+// through backends consistently (matters on the rare Windows dev box). Any
+// directory below dir, at any depth, whose name is in skipDirs is pruned
+// before it's descended into.
+func listDirRecursive(dir string, skipDirs ...string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir {
+				for _, skip := range skipDirs {
+					if entry.Name() == skip {
+						return filepath.SkipDir
+					}
+				}
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (s *fsStorage) Delete(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+func init() {
+	registerStorageBackend("git", func(outputDir string) (Storage, error) {
+		return newGitStorage()
+	})
+}
+
+// gitStorage stores briefs in a Git repository: it clones to a temp dir the
+// first time it's needed and writes through to that working copy, pushing
+// once handlePush is done with it (see commitAndPush).
+type gitStorage struct {
+	dir    string
+	branch string
+
+	// mu serializes every change to the working tree (Put, Delete, the
+	// refresh pull, and commitAndPush); Get and List read without it. Instances are
+	// cached and reused across requests (see gitStorageCache), and streaming
+	// mode can run several pushes concurrently, so without this two pushes
+	// would race on the same `git add`/`commit`/`push` and corrupt each
+	// other's results.
+	mu sync.Mutex
+}
+
+// gitStorageCache holds one clone per remote+branch for the life of the
+// process. newStorage is called fresh on every request, and in streaming
+// mode a process handles many requests, so without this a clone would be
+// made (and never cleaned up) on every single push/pull/status call.
+var (
+	gitStorageMu    sync.Mutex
+	gitStorageCache = map[string]*gitStorage{}
+)
+
+func newGitStorage() (*gitStorage, error) {
+	remote := os.Getenv("SHAPE_SYNC_GIT_REMOTE")
+	if remote == "" {
+		return nil, fmt.Errorf("SHAPE_SYNC_GIT_REMOTE is required for the git backend")
+	}
+	branch := os.Getenv("SHAPE_SYNC_GIT_BRANCH")
+	if branch == "" {
+		branch = "main"
+	}
+	cacheKey := remote + "@" + branch
+
+	gitStorageMu.Lock()
+	defer gitStorageMu.Unlock()
+
+	if s, ok := gitStorageCache[cacheKey]; ok {
+		s.mu.Lock()
+		err := runGit(s.dir, "pull", "--ff-only", "origin", branch)
+		s.mu.Unlock()
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh existing clone of %s: %w", remote, err)
+		}
+		return s, nil
+	}
+
+	dir, err := os.MkdirTemp("", "shape-sync-git-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone dir: %w", err)
+	}
+
+	if err := runGit(dir, "clone", "--branch", branch, "--single-branch", remote, "."); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to clone %s: %w", remote, err)
+	}
+
+	s := &gitStorage{dir: dir, branch: branch}
+	gitStorageCache[cacheKey] = s
+	return s, nil
+}
+
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %v: %w: %s", args, err, out)
+	}
+	return nil
+}
+
+func (s *gitStorage) Put(name string, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFileAtomic(filepath.Join(s.dir, name), content)
+}
+
+func (s *gitStorage) Get(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+func (s *gitStorage) List() ([]string, error) {
+	return listDirRecursive(s.dir, ".git")
+}
+
+func (s *gitStorage) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// commitAndPush stages every change made through Put/Delete, commits with a
+// message summarizing the push, and pushes it to the configured branch.
+// It's a no-op if nothing changed. Callers must not hold s.mu; it takes the
+// lock itself so the add/commit/push sequence is atomic with respect to
+// concurrent Put/Delete calls against the same clone.
+func (s *gitStorage) commitAndPush(briefCount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := runGit(s.dir, "add", "-A"); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("git", "status", "--porcelain")
+	cmd.Dir = s.dir
+	status, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git status: %w", err)
+	}
+	if len(status) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("shape-sync: push %d briefs", briefCount)
+	if err := runGit(s.dir, "commit", "-m", message); err != nil {
+		return err
+	}
+	return runGit(s.dir, "push", "origin", "HEAD:"+s.branch)
+}
@@ -0,0 +1,100 @@
+//go:build s3
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+func init() {
+	registerStorageBackend("s3", func(outputDir string) (Storage, error) {
+		return newS3Storage()
+	})
+}
+
+// s3Storage stores briefs as objects in an S3-compatible bucket, configured
+// entirely through environment variables so it drops in next to the fs
+// backend without new CLI flags.
+type s3Storage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func newS3Storage() (*s3Storage, error) {
+	endpoint := os.Getenv("SHAPE_SYNC_S3_ENDPOINT")
+	bucket := os.Getenv("SHAPE_SYNC_S3_BUCKET")
+	if endpoint == "" || bucket == "" {
+		return nil, fmt.Errorf("SHAPE_SYNC_S3_ENDPOINT and SHAPE_SYNC_S3_BUCKET are required for the s3 backend")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds: credentials.NewStaticV4(
+			os.Getenv("SHAPE_SYNC_S3_ACCESS_KEY"),
+			os.Getenv("SHAPE_SYNC_S3_SECRET_KEY"),
+			"",
+		),
+		Secure: os.Getenv("SHAPE_SYNC_S3_USE_SSL") != "false",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	return &s3Storage{
+		client: client,
+		bucket: bucket,
+		prefix: strings.Trim(os.Getenv("SHAPE_SYNC_S3_PREFIX"), "/"),
+	}, nil
+}
+
+func (s *s3Storage) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+func (s *s3Storage) Put(name string, content []byte) error {
+	_, err := s.client.PutObject(
+		context.Background(), s.bucket, s.key(name),
+		bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{ContentType: "text/markdown"},
+	)
+	return err
+}
+
+func (s *s3Storage) Get(name string) ([]byte, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(obj)
+}
+
+func (s *s3Storage) List() ([]string, error) {
+	var names []string
+	prefix := s.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	for obj := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		names = append(names, strings.TrimPrefix(obj.Key, prefix))
+	}
+	return names, nil
+}
+
+func (s *s3Storage) Delete(name string) error {
+	return s.client.RemoveObject(context.Background(), s.bucket, s.key(name), minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const snapshotDirName = ".shape-sync"
+
+const (
+	conflictMarkerLocal  = "<<<<<<< local"
+	conflictMarkerSplit  = "======="
+	conflictMarkerRemote = ">>>>>>> remote"
+)
+
+// syncSnapshot is the last-synced state of a single brief, kept on disk as
+// the merge ancestor for three-way conflict detection between local edits
+// and incoming pushes.
+type syncSnapshot struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Appetite string `json:"appetite"`
+	Body     string `json:"body"`
+	Hash     string `json:"hash"`
+}
+
+func snapshotDir(outputDir string) string {
+	return filepath.Join(outputDir, snapshotDirName)
+}
+
+func snapshotPath(outputDir, briefID string) string {
+	return filepath.Join(snapshotDir(outputDir), briefID+".json")
+}
+
+// stableContent builds the part of a Brief that matters for conflict
+// detection, excluding volatile fields like the synced_at timestamp.
+func stableContent(brief Brief) string {
+	return brief.ID + "\x00" + brief.Title + "\x00" + brief.Status + "\x00" + brief.Appetite + "\x00" + brief.Body
+}
+
+func contentHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadSnapshot(outputDir, briefID string) (syncSnapshot, bool) {
+	data, err := os.ReadFile(snapshotPath(outputDir, briefID))
+	if err != nil {
+		return syncSnapshot{}, false
+	}
+	var snap syncSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return syncSnapshot{}, false
+	}
+	return snap, true
+}
+
+func saveSnapshot(outputDir string, brief Brief) error {
+	snap := syncSnapshot{
+		ID:       brief.ID,
+		Title:    brief.Title,
+		Status:   brief.Status,
+		Appetite: brief.Appetite,
+		Body:     brief.Body,
+		Hash:     contentHash(stableContent(brief)),
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(snapshotPath(outputDir, brief.ID), data)
+}
+
+// writeFileAtomic writes data to a temp file alongside path and renames it
+// into place, so a crash or partial write never leaves a corrupted file or
+// snapshot behind.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// hasConflictMarkers reports whether content still contains unresolved
+// three-way merge conflict markers left over from a previous push.
+func hasConflictMarkers(content string) bool {
+	return strings.Contains(content, conflictMarkerLocal) &&
+		strings.Contains(content, conflictMarkerRemote)
+}
+
+// mergeBodies performs a line-based three-way merge of a brief's body: it
+// compares the ancestor (last-synced) version against both the local
+// on-disk version and the incoming remote version. Hunks separated by at
+// least one unchanged ancestor line are merged automatically; overlapping
+// or directly adjacent hunks that differ are replaced with conflict markers.
+func mergeBodies(ancestor, local, remote string) (merged string, conflict bool) {
+	mergedLines, conflict := threeWayMergeLines(
+		strings.Split(ancestor, "\n"),
+		strings.Split(local, "\n"),
+		strings.Split(remote, "\n"),
+	)
+	return strings.Join(mergedLines, "\n"), conflict
+}
+
+// hunk is a contiguous range of ancestor lines replaced by new content.
+type hunk struct {
+	start, end int
+	lines      []string
+}
+
+// diffHunks computes the line hunks needed to turn ancestor into modified,
+// using a classic LCS-based diff.
+func diffHunks(ancestor, modified []string) []hunk {
+	n, m := len(ancestor), len(modified)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case ancestor[i] == modified[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var hunks []hunk
+	i, j := 0, 0
+	for i < n || j < m {
+		if i < n && j < m && ancestor[i] == modified[j] {
+			i++
+			j++
+			continue
+		}
+		aStart, mStart := i, j
+		for i < n && j < m && ancestor[i] != modified[j] {
+			if lcs[i+1][j] >= lcs[i][j+1] {
+				i++
+			} else {
+				j++
+			}
+		}
+		for i < n && j == m {
+			i++
+		}
+		for j < m && i == n {
+			j++
+		}
+		hunks = append(hunks, hunk{start: aStart, end: i, lines: append([]string{}, modified[mStart:j]...)})
+	}
+	return hunks
+}
+
+// threeWayMergeLines merges local and remote line hunks against their
+// common ancestor. Hunks separated by at least one unchanged ancestor line
+// are applied independently; hunks whose ranges overlap or abut are judged
+// together, and if the two sides' versions of that range differ it is
+// replaced with <<<<<<< local / ======= / >>>>>>> remote conflict markers.
+func threeWayMergeLines(ancestor, local, remote []string) ([]string, bool) {
+	localHunks := diffHunks(ancestor, local)
+	remoteHunks := diffHunks(ancestor, remote)
+
+	var merged []string
+	conflict := false
+	pos, li, ri := 0, 0, 0
+
+	for li < len(localHunks) || ri < len(remoteHunks) {
+		next := len(ancestor)
+		if li < len(localHunks) && localHunks[li].start < next {
+			next = localHunks[li].start
+		}
+		if ri < len(remoteHunks) && remoteHunks[ri].start < next {
+			next = remoteHunks[ri].start
+		}
+		if pos < next {
+			merged = append(merged, ancestor[pos:next]...)
+			pos = next
+		}
+
+		// Grow the cluster to a fixed point so hunks from either side that
+		// transitively overlap (via a hunk on the other side) end up
+		// judged together instead of mis-detected as independent.
+		clusterEnd := pos
+		var lGroup, rGroup []hunk
+		for {
+			grew := false
+			for li < len(localHunks) && localHunks[li].start <= clusterEnd {
+				h := localHunks[li]
+				lGroup = append(lGroup, h)
+				if h.end > clusterEnd {
+					clusterEnd = h.end
+				}
+				li++
+				grew = true
+			}
+			for ri < len(remoteHunks) && remoteHunks[ri].start <= clusterEnd {
+				h := remoteHunks[ri]
+				rGroup = append(rGroup, h)
+				if h.end > clusterEnd {
+					clusterEnd = h.end
+				}
+				ri++
+				grew = true
+			}
+			if !grew {
+				break
+			}
+		}
+
+		switch {
+		case len(lGroup) == 0:
+			for _, h := range rGroup {
+				merged = append(merged, h.lines...)
+			}
+		case len(rGroup) == 0:
+			for _, h := range lGroup {
+				merged = append(merged, h.lines...)
+			}
+		default:
+			localLines := flattenHunks(ancestor, lGroup, pos, clusterEnd)
+			remoteLines := flattenHunks(ancestor, rGroup, pos, clusterEnd)
+			if linesEqual(localLines, remoteLines) {
+				merged = append(merged, localLines...)
+			} else {
+				conflict = true
+				merged = append(merged, conflictMarkerLocal)
+				merged = append(merged, localLines...)
+				merged = append(merged, conflictMarkerSplit)
+				merged = append(merged, remoteLines...)
+				merged = append(merged, conflictMarkerRemote)
+			}
+		}
+		pos = clusterEnd
+	}
+	if pos < len(ancestor) {
+		merged = append(merged, ancestor[pos:]...)
+	}
+	return merged, conflict
+}
+
+// flattenHunks rebuilds one side's version of the ancestor range
+// [start, end) by applying that side's hunks and keeping the ancestor lines
+// between and around them.
+func flattenHunks(ancestor []string, hunks []hunk, start, end int) []string {
+	var out []string
+	pos := start
+	for _, h := range hunks {
+		out = append(out, ancestor[pos:h.start]...)
+		out = append(out, h.lines...)
+		pos = h.end
+	}
+	return append(out, ancestor[pos:end]...)
+}
+
+func linesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
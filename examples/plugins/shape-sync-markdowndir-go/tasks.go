@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const tasksSectionHeading = "## Tasks"
+
+var taskHeadingPattern = regexp.MustCompile(`(?m)^### \[(.+?)\] (.*)$`)
+var checklistItemPattern = regexp.MustCompile(`^- \[([ xX])\] (.*)$`)
+
+// taskLayout reads SHAPE_SYNC_MARKDOWN_TASK_LAYOUT, defaulting to rendering
+// tasks inline in their parent brief's markdown file. Set it to "files" to
+// write sibling tasks/<brief-id>/<task-id>-<slug>.md files instead.
+func taskLayout() string {
+	if os.Getenv("SHAPE_SYNC_MARKDOWN_TASK_LAYOUT") == "files" {
+		return "files"
+	}
+	return "inline"
+}
+
+// taskPath builds the storage key for a sibling task file under the
+// "tasks/<brief-id>/" prefix.
+func taskPath(briefID string, task Task) string {
+	return "tasks/" + briefID + "/" + taskFilename(task)
+}
+
+func taskFilename(task Task) string {
+	return fmt.Sprintf("%s-%s.md", task.ID, slugify(task.Title))
+}
+
+// generateTaskMarkdown renders a task as a standalone markdown file with
+// YAML frontmatter and its checklist as the body. Task carries the
+// frontmatter's yaml tags directly (see protocol.go), so it's marshaled
+// as-is rather than through a shadow struct.
+func generateTaskMarkdown(task Task) string {
+	header, err := yaml.Marshal(task)
+	if err != nil {
+		header = []byte("id: " + task.ID + "\n")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	buf.WriteString(renderChecklist(task.Checklist))
+	return buf.String()
+}
+
+// parseTaskMarkdown parses a standalone sibling task file back into a Task.
+func parseTaskMarkdown(content string) (Task, bool) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return Task{}, false
+	}
+
+	rest := content[4:]
+	endIndex := strings.Index(rest, "\n---\n")
+	if endIndex == -1 {
+		return Task{}, false
+	}
+
+	header := rest[:endIndex]
+	body := strings.TrimPrefix(rest[endIndex+5:], "\n")
+
+	var task Task
+	if err := yaml.Unmarshal([]byte(header), &task); err != nil {
+		return Task{}, false
+	}
+	task.Checklist = parseChecklist(body)
+
+	return task, task.ID != ""
+}
+
+// renderChecklist renders checklist items as a markdown task list.
+func renderChecklist(items []ChecklistItem) string {
+	var buf bytes.Buffer
+	for _, item := range items {
+		mark := " "
+		if item.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(&buf, "- [%s] %s\n", mark, item.Text)
+	}
+	return buf.String()
+}
+
+// parseChecklist parses a markdown task list back into checklist items.
+func parseChecklist(body string) []ChecklistItem {
+	var items []ChecklistItem
+	for _, line := range strings.Split(body, "\n") {
+		m := checklistItemPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		items = append(items, ChecklistItem{
+			Text: m[2],
+			Done: strings.EqualFold(m[1], "x"),
+		})
+	}
+	return items
+}
+
+// renderTasksSection renders a brief's tasks as an inline "## Tasks" section
+// to append to the brief's markdown content.
+func renderTasksSection(tasks []Task) string {
+	var buf bytes.Buffer
+	buf.WriteString(tasksSectionHeading + "\n\n")
+	for _, task := range tasks {
+		fmt.Fprintf(&buf, "### [%s] %s\n", task.ID, task.Title)
+		fmt.Fprintf(&buf, "status: %s\n", task.Status)
+		if task.Assignee != "" {
+			fmt.Fprintf(&buf, "assignee: %s\n", task.Assignee)
+		}
+		buf.WriteString("\n")
+		buf.WriteString(renderChecklist(task.Checklist))
+		buf.WriteString("\n")
+	}
+	return strings.TrimRight(buf.String(), "\n") + "\n"
+}
+
+// splitTasksSection separates a brief file's own content from a trailing
+// inline "## Tasks" section, if present.
+func splitTasksSection(content string) (rest string, tasksSection string, ok bool) {
+	if strings.HasPrefix(content, tasksSectionHeading) {
+		return "", content, true
+	}
+	idx := strings.Index(content, "\n"+tasksSectionHeading)
+	if idx == -1 {
+		return content, "", false
+	}
+	return strings.TrimRight(content[:idx], "\n") + "\n", content[idx+1:], true
+}
+
+// parseTasksSection parses an inline "## Tasks" section back into the
+// Tasks belonging to briefID.
+func parseTasksSection(section string, briefID string) []Task {
+	matches := taskHeadingPattern.FindAllStringSubmatchIndex(section, -1)
+
+	var tasks []Task
+	for i, m := range matches {
+		id := section[m[2]:m[3]]
+		title := section[m[4]:m[5]]
+
+		blockEnd := len(section)
+		if i+1 < len(matches) {
+			blockEnd = matches[i+1][0]
+		}
+		block := section[m[1]:blockEnd]
+
+		task := Task{ID: id, BriefID: briefID, Title: title}
+		for _, line := range strings.Split(block, "\n") {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(trimmed, "status:"):
+				task.Status = strings.TrimSpace(strings.TrimPrefix(trimmed, "status:"))
+			case strings.HasPrefix(trimmed, "assignee:"):
+				task.Assignee = strings.TrimSpace(strings.TrimPrefix(trimmed, "assignee:"))
+			}
+		}
+		task.Checklist = parseChecklist(block)
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// findBriefFile locates the storage key for a brief's markdown file by ID,
+// since filenames also carry a title slug.
+func findBriefFile(storage Storage, briefID string) (string, error) {
+	names, err := storage.List()
+	if err != nil {
+		return "", err
+	}
+	prefix := briefID + "-"
+	for _, name := range names {
+		if !strings.Contains(name, "/") && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".md") {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no markdown file found for brief %s", briefID)
+}
+
+// pushTasks writes tasks through storage using the configured task layout
+// and returns their id mappings plus any per-task errors. If ctx's deadline
+// fires partway through, it returns ctx.Err() alongside whatever mappings
+// and errors were produced so far, so the caller can report partial
+// progress instead of blocking on a slow backend.
+func pushTasks(ctx context.Context, storage Storage, tasks []Task) ([]IdMapping, []string, error) {
+	if len(tasks) == 0 {
+		return nil, nil, nil
+	}
+
+	var order []string
+	byBrief := map[string][]Task{}
+	for _, task := range tasks {
+		if _, seen := byBrief[task.BriefID]; !seen {
+			order = append(order, task.BriefID)
+		}
+		byBrief[task.BriefID] = append(byBrief[task.BriefID], task)
+	}
+
+	var mappings []IdMapping
+	var errs []string
+	layout := taskLayout()
+
+	for _, briefID := range order {
+		select {
+		case <-ctx.Done():
+			return mappings, errs, ctx.Err()
+		default:
+		}
+
+		briefTasks := byBrief[briefID]
+
+		if layout == "files" {
+			for _, task := range briefTasks {
+				path := taskPath(briefID, task)
+				putErr := runWithContext(ctx, func() error {
+					return storage.Put(path, []byte(generateTaskMarkdown(task)))
+				})
+				if putErr != nil {
+					if putErr == ctx.Err() {
+						return mappings, errs, ctx.Err()
+					}
+					errs = append(errs, fmt.Sprintf("task %s: %v", task.ID, putErr))
+					continue
+				}
+				mappings = append(mappings, IdMapping{LocalID: task.ID, RemoteID: path, EntityType: "task"})
+			}
+			continue
+		}
+
+		briefPath, err := findBriefFile(storage, briefID)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("brief %s: %v", briefID, err))
+			continue
+		}
+
+		var content []byte
+		getErr := runWithContext(ctx, func() error {
+			var err error
+			content, err = storage.Get(briefPath)
+			return err
+		})
+		if getErr != nil {
+			if getErr == ctx.Err() {
+				return mappings, errs, ctx.Err()
+			}
+			errs = append(errs, fmt.Sprintf("brief %s: %v", briefID, getErr))
+			continue
+		}
+
+		rest, _, _ := splitTasksSection(string(content))
+		newContent := strings.TrimRight(rest, "\n") + "\n\n" + renderTasksSection(briefTasks)
+		putErr := runWithContext(ctx, func() error {
+			return storage.Put(briefPath, []byte(newContent))
+		})
+		if putErr != nil {
+			if putErr == ctx.Err() {
+				return mappings, errs, ctx.Err()
+			}
+			errs = append(errs, fmt.Sprintf("brief %s: %v", briefID, putErr))
+			continue
+		}
+
+		for _, task := range briefTasks {
+			mappings = append(mappings, IdMapping{LocalID: task.ID, RemoteID: briefPath, EntityType: "task"})
+		}
+	}
+
+	return mappings, errs, nil
+}
+
+// collectTasks gathers every task currently in storage, whether stored
+// inline in a brief's markdown file or as sibling task files under
+// tasks/<brief-id>/, since a directory synced with different
+// SHAPE_SYNC_MARKDOWN_TASK_LAYOUT settings over time may contain both. If
+// ctx's deadline fires partway through, it stops and returns whatever it
+// has collected so far rather than blocking on a slow backend.
+func collectTasks(ctx context.Context, storage Storage) []Task {
+	names, err := storage.List()
+	if err != nil {
+		return nil
+	}
+
+	var tasks []Task
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			return tasks
+		default:
+		}
+
+		switch {
+		case !strings.Contains(name, "/") && strings.HasSuffix(name, ".md"):
+			var content []byte
+			if err := runWithContext(ctx, func() error {
+				var getErr error
+				content, getErr = storage.Get(name)
+				return getErr
+			}); err != nil {
+				continue
+			}
+
+			rest, tasksSection, hasTasks := splitTasksSection(string(content))
+			if !hasTasks {
+				continue
+			}
+
+			briefID := strings.TrimSuffix(name, ".md")
+			if brief, ok := parseMarkdown(rest); ok {
+				briefID = brief.ID
+			}
+			tasks = append(tasks, parseTasksSection(tasksSection, briefID)...)
+
+		case strings.HasPrefix(name, "tasks/") && strings.HasSuffix(name, ".md"):
+			var content []byte
+			if err := runWithContext(ctx, func() error {
+				var getErr error
+				content, getErr = storage.Get(name)
+				return getErr
+			}); err != nil {
+				continue
+			}
+			if task, ok := parseTaskMarkdown(string(content)); ok {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
+	return tasks
+}